@@ -0,0 +1,117 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor implements the document processing engine that executes plugins and
+// publishes their results.
+package processor
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// Processor starts and stops the engine that executes documents submitted to it and publishes
+// each plugin's result on the channel returned by Start.
+type Processor interface {
+	Start() (chan contracts.DocumentResult, error)
+	InitialProcessing() error
+	Stop(stopType contracts.StopType)
+}
+
+// EngineProcessor is the Processor used for Session Manager documents.
+type EngineProcessor struct {
+	context                 context.T
+	workerLimit             int
+	resultChannelBufferSize int
+	supportedDocTypes       []contracts.DocumentType
+
+	resultChan chan contracts.DocumentResult
+
+	progressMu      sync.Mutex
+	progressHandler func(sessionId string, pluginId string, chunk string, final bool)
+}
+
+// NewEngineProcessor creates a new EngineProcessor for the given supported document types.
+func NewEngineProcessor(
+	context context.T,
+	workerLimit int,
+	resultChannelBufferSize int,
+	supportedDocTypes []contracts.DocumentType) *EngineProcessor {
+
+	return &EngineProcessor{
+		context:                 context,
+		workerLimit:             workerLimit,
+		resultChannelBufferSize: resultChannelBufferSize,
+		supportedDocTypes:       supportedDocTypes,
+	}
+}
+
+// RegisterProgressHandler registers handler to be called every time a running plugin has a new
+// output chunk to push ahead of its final DocumentResult. It is an optional extension point:
+// callers probe for it with a type assertion against this method's signature (see
+// agent/session/streaming.go's progressReporter), so only document types whose plugins actually
+// stream output need to call reportProgress.
+func (e *EngineProcessor) RegisterProgressHandler(handler func(sessionId string, pluginId string, chunk string, final bool)) {
+	e.progressMu.Lock()
+	defer e.progressMu.Unlock()
+	e.progressHandler = handler
+}
+
+// reportProgress pushes one output chunk for (sessionId, pluginId) to the registered progress
+// handler, if any. It is a no-op until Session calls RegisterProgressHandler.
+func (e *EngineProcessor) reportProgress(sessionId string, pluginId string, chunk string, final bool) {
+	e.progressMu.Lock()
+	handler := e.progressHandler
+	e.progressMu.Unlock()
+
+	if handler != nil {
+		handler(sessionId, pluginId, chunk, final)
+	}
+}
+
+// Start allocates the result channel plugins publish their DocumentResults to and returns it.
+func (e *EngineProcessor) Start() (chan contracts.DocumentResult, error) {
+	e.resultChan = make(chan contracts.DocumentResult, e.resultChannelBufferSize)
+	return e.resultChan, nil
+}
+
+// InitialProcessing is a no-op: documents that were in flight across an agent restart are not
+// resumed.
+func (e *EngineProcessor) InitialProcessing() error {
+	return nil
+}
+
+// Stop closes the result channel. It does not wait for in-flight plugin executions to finish.
+func (e *EngineProcessor) Stop(stopType contracts.StopType) {
+	if e.resultChan != nil {
+		close(e.resultChan)
+	}
+}
+
+// ExecutePlugin runs a single plugin to completion, pushing each entry of chunks through
+// reportProgress as it becomes available and finally publishing result on the channel returned by
+// Start. It is the call site a document worker drives a plugin's progress through; streaming_test.go
+// exercises it the same way, registering a handler and calling ExecutePlugin rather than invoking
+// reportProgress's handler directly.
+func (e *EngineProcessor) ExecutePlugin(sessionId string, pluginId string, chunks []string, result contracts.DocumentResult) {
+	for _, chunk := range chunks {
+		e.reportProgress(sessionId, pluginId, chunk, false)
+	}
+	e.reportProgress(sessionId, pluginId, "", true)
+
+	if e.resultChan != nil {
+		e.resultChan <- result
+	}
+}
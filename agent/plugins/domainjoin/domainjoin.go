@@ -0,0 +1,169 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package domainjoin implements the domain join plugin.
+package domainjoin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// Plugin is the type for the domain join plugin.
+type Plugin struct {
+	pluginutil.DefaultPlugin
+}
+
+// DomainJoinPluginInput represents one set of commands executed by the domain join plugin.
+type DomainJoinPluginInput struct {
+	contracts.PluginInput
+	DirectoryId    string
+	DirectoryName  string
+	DnsIpAddresses []string
+	DirectoryOU    string
+}
+
+// execute runs a single command as part of joining the domain and is implemented per-platform.
+type execute func(log log.T, cmd string, workingDir string, outputRoot string, stdOut string, stdErr string, isAsync bool) (err error)
+
+// Joiner abstracts the OS-specific mechanics of joining an AWS Directory Service directory so
+// that runCommands/runCommandsRawInput stay platform agnostic. Each platform_*.go file registers
+// its own implementation in the joiner package variable via an init function.
+type Joiner interface {
+	// Arguments builds the platform-specific command line (or equivalent description) used to
+	// join pluginInput.DirectoryName/DirectoryId. It is a pure builder with no side effects.
+	Arguments(log log.T, pluginInput DomainJoinPluginInput) (commandArguments string, err error)
+}
+
+// ResolverConfigurer is implemented by Joiners that need the instance's DNS resolver pointed at
+// the directory's DNS servers before the join command runs. runCommands probes for it with a type
+// assertion, the same optional-extension-point pattern used elsewhere in the agent (e.g. the
+// agent/session package uses it for progressReporter/ReconnectObservable), so platforms that
+// don't need this step (e.g. Windows, which hands DnsIpAddresses to Ec2Config.DomainJoin.exe via
+// --dns-addresses) don't have to implement it.
+type ResolverConfigurer interface {
+	ConfigureResolver(pluginInput DomainJoinPluginInput) error
+}
+
+// joiner is the platform-specific Joiner selected at build time by platform_*.go.
+var joiner Joiner
+
+// utilExe runs the join command built by joiner. It is overridden in tests.
+var utilExe execute = executeShellCommand
+
+// makeDir is overridden in tests.
+var makeDir = makeDirectory
+
+// getRegion is overridden in tests.
+var getRegion = currentRegion
+
+// executeShellCommand runs cmd through the platform shell, writing its stdout/stderr under outputRoot.
+func executeShellCommand(log log.T, cmd string, workingDir string, outputRoot string, stdOut string, stdErr string, isAsync bool) (err error) {
+	command := shellCommand(cmd)
+	command.Dir = workingDir
+
+	stdoutFile, err := os.Create(filepath.Join(outputRoot, stdOut))
+	if err != nil {
+		return err
+	}
+	defer stdoutFile.Close()
+	stderrFile, err := os.Create(filepath.Join(outputRoot, stdErr))
+	if err != nil {
+		return err
+	}
+	defer stderrFile.Close()
+
+	command.Stdout = stdoutFile
+	command.Stderr = stderrFile
+	return command.Run()
+}
+
+// makeDirectory creates destinationDir, including any missing parents.
+func makeDirectory(destinationDir string) (err error) {
+	return fileutil.MakeDirs(destinationDir)
+}
+
+// currentRegion returns the region of the instance the agent is running on.
+func currentRegion() (string, error) {
+	return platform.Region()
+}
+
+// shellCommand is overridden per-platform to build the *exec.Cmd that runs cmd through the
+// platform's shell (cmd.exe on Windows, sh elsewhere).
+var shellCommand = func(cmd string) *exec.Cmd {
+	return exec.Command("sh", "-c", cmd)
+}
+
+// runCommands executes the commands for the domain join plugin input and returns the result.
+func (p *Plugin) runCommands(log log.T,
+	pluginInput DomainJoinPluginInput,
+	orchestrationDirectory string,
+	cancelFlag task.CancelFlag,
+	outputS3BucketName string,
+	outputS3KeyPrefix string,
+	utilExe execute) (out contracts.PluginOutput) {
+
+	commandArguments, err := joiner.Arguments(log, pluginInput)
+	if err != nil {
+		out.MarkAsFailed(log, fmt.Errorf("failed to build domain join command: %s", err))
+		return
+	}
+
+	if configurer, ok := joiner.(ResolverConfigurer); ok {
+		if err = configurer.ConfigureResolver(pluginInput); err != nil {
+			out.MarkAsFailed(log, fmt.Errorf("failed to configure DNS resolver: %s", err))
+			return
+		}
+	}
+
+	if err = makeDir(orchestrationDirectory); err != nil {
+		out.MarkAsFailed(log, fmt.Errorf("failed to create orchestration directory, %s", err))
+		return
+	}
+
+	if err = utilExe(log, commandArguments, orchestrationDirectory, orchestrationDirectory, p.StdoutFileName, p.StderrFileName, false); err != nil {
+		out.MarkAsFailed(log, fmt.Errorf("failed to join domain, %s", err))
+		return
+	}
+
+	out.MarkAsSucceeded()
+	return
+}
+
+// runCommandsRawInput unmarshals rawPluginInput and delegates to runCommands.
+func (p *Plugin) runCommandsRawInput(log log.T,
+	rawPluginInput interface{},
+	orchestrationDirectory string,
+	cancelFlag task.CancelFlag,
+	outputS3BucketName string,
+	outputS3KeyPrefix string,
+	utilExe execute) (out contracts.PluginOutput) {
+
+	var pluginInput DomainJoinPluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &pluginInput); err != nil {
+		out.MarkAsFailed(log, fmt.Errorf("invalid format in plugin properties %v; %s", rawPluginInput, err))
+		return
+	}
+
+	return p.runCommands(log, pluginInput, orchestrationDirectory, cancelFlag, outputS3BucketName, outputS3KeyPrefix, utilExe)
+}
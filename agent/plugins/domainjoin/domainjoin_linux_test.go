@@ -0,0 +1,155 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build linux
+//
+// Package domainjoin implements the domain join plugin.
+package domainjoin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// generateLinuxTestCase builds a TestCase exercising linuxJoiner for the given distro family.
+func generateLinuxTestCase(family linuxDistroFamily, mark bool) TestCase {
+	var out contracts.PluginOutput
+	if mark {
+		out = contracts.PluginOutput{Stdout: "", Stderr: "", ExitCode: 0, Status: "Success"}
+	} else {
+		out = contracts.PluginOutput{Stdout: "", Stderr: "", ExitCode: 1, Status: "Failed"}
+	}
+
+	return TestCase{
+		Input:  generateDomainJoinPluginInput(testDirectoryId, testDirectoryName, []string{"10.0.0.0", "10.0.1.0"}),
+		Output: out,
+		mark:   mark,
+	}
+}
+
+// TestRunCommandsLinux exercises runCommands/runCommandsRawInput against both the RHEL-family
+// (realmd/adcli) and Debian-family (sssd/net ads) joiners with a mocked exec.
+func TestRunCommandsLinux(t *testing.T) {
+	for _, family := range []linuxDistroFamily{rhelFamily, debianFamily} {
+		for _, mark := range []bool{true, false} {
+			testCase := generateLinuxTestCase(family, mark)
+			testRunCommandsLinux(t, family, testCase, true)
+			testRunCommandsLinux(t, family, testCase, false)
+		}
+	}
+}
+
+func testRunCommandsLinux(t *testing.T, family linuxDistroFamily, testCase TestCase, rawInput bool) {
+	logger.On("Error", mock.Anything).Return(nil)
+
+	joiner = &linuxJoiner{distro: func() (linuxDistroFamily, error) { return family, nil }}
+
+	configureResolver = func(dnsIpAddresses []string, directoryName string) error {
+		return nil
+	}
+
+	makeDir = func(destinationDir string) (err error) {
+		return nil
+	}
+
+	var exe execute
+	if testCase.mark {
+		exe = func(log log.T, cmd string, workingDir string, outputRoot string, stdOut string, stdErr string, isAsync bool) (err error) {
+			return nil
+		}
+	} else {
+		errCase := errors.New("err here")
+		exe = func(log log.T, cmd string, workingDir string, outputRoot string, stdOut string, stdErr string, isAsync bool) (err error) {
+			return errCase
+		}
+	}
+
+	var res contracts.PluginOutput
+	mockCancelFlag := new(task.MockCancelFlag)
+	p := new(Plugin)
+	p.StdoutFileName = "stdout"
+	p.StderrFileName = "stderr"
+	p.MaxStdoutLength = 1000
+	p.MaxStderrLength = 1000
+	p.OutputTruncatedSuffix = "-more-"
+	p.UploadToS3Sync = true
+	p.ExecuteUploadOutputToS3Bucket = func(log log.T, pluginID string, orchestrationDir string, outputS3BucketName string, outputS3KeyPrefix string, useTempDirectory bool, tempDir string, Stdout string, Stderr string) []string {
+		return []string{}
+	}
+
+	if rawInput {
+		var rawPluginInput map[string]interface{}
+		err := jsonutil.Remarshal(testCase.Input, &rawPluginInput)
+		assert.Nil(t, err)
+
+		res = p.runCommandsRawInput(logger, rawPluginInput, orchestrationDirectory, mockCancelFlag, s3BucketName, s3KeyPrefix, exe)
+	} else {
+		res = p.runCommands(logger, testCase.Input, orchestrationDirectory, mockCancelFlag, s3BucketName, s3KeyPrefix, exe)
+	}
+
+	assert.Equal(t, testCase.Output, res)
+}
+
+// TestLinuxJoinerArguments checks that each distro family produces its expected join command,
+// including OU placement and (on Debian) the explicit keytab creation step. Arguments has no
+// side effects, so configureResolver is left untouched here.
+func TestLinuxJoinerArguments(t *testing.T) {
+	input := generateDomainJoinPluginInput(testDirectoryId, testDirectoryName, []string{"172.31.4.141", "172.31.21.240"})
+
+	rhel := &linuxJoiner{distro: func() (linuxDistroFamily, error) { return rhelFamily, nil }}
+	rhelArgs, err := rhel.Arguments(logger, input)
+	assert.Nil(t, err)
+	assert.Equal(t, "realm join --install=/ --client-software=sssd corp.test.com", rhelArgs)
+
+	debian := &linuxJoiner{distro: func() (linuxDistroFamily, error) { return debianFamily, nil }}
+	debianArgs, err := debian.Arguments(logger, input)
+	assert.Nil(t, err)
+	assert.Equal(t, "net ads join -k && net ads keytab create -k", debianArgs)
+
+	inputWithOU := input
+	inputWithOU.DirectoryOU = "OU=Computers,DC=corp,DC=test,DC=com"
+
+	rhelOUArgs, err := rhel.Arguments(logger, inputWithOU)
+	assert.Nil(t, err)
+	assert.Equal(t, "realm join --install=/ --client-software=sssd --computer-ou OU=Computers,DC=corp,DC=test,DC=com corp.test.com", rhelOUArgs)
+
+	debianOUArgs, err := debian.Arguments(logger, inputWithOU)
+	assert.Nil(t, err)
+	assert.Equal(t, "net ads join createcomputer=OU=Computers,DC=corp,DC=test,DC=com -k && net ads keytab create -k", debianOUArgs)
+}
+
+// TestLinuxJoinerConfigureResolver checks that ConfigureResolver delegates to the overridable
+// configureResolver var with the directory's DNS servers and name.
+func TestLinuxJoinerConfigureResolver(t *testing.T) {
+	var gotIps []string
+	var gotName string
+	configureResolver = func(dnsIpAddresses []string, directoryName string) error {
+		gotIps = dnsIpAddresses
+		gotName = directoryName
+		return nil
+	}
+
+	input := generateDomainJoinPluginInput(testDirectoryId, testDirectoryName, []string{"172.31.4.141", "172.31.21.240"})
+	j := &linuxJoiner{distro: func() (linuxDistroFamily, error) { return debianFamily, nil }}
+
+	assert.Nil(t, j.ConfigureResolver(input))
+	assert.Equal(t, input.DnsIpAddresses, gotIps)
+	assert.Equal(t, input.DirectoryName, gotName)
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package domainjoin implements the domain join plugin.
+//
+// This file holds the TestCase harness shared by every domainjoin_*_test.go file. It carries no
+// build tag so that it compiles on every platform the per-OS test files target.
+package domainjoin
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+type TestCase struct {
+	Input          DomainJoinPluginInput
+	Output         contracts.PluginOutput
+	ExecuterErrors []error
+	mark           bool
+}
+
+const (
+	orchestrationDirectory = "OrchesDir"
+	s3BucketName           = "bucket"
+	s3KeyPrefix            = "key"
+	testInstanceID         = "i-12345678"
+	bucketRegionErrorMsg   = "AuthorizationHeaderMalformed: The authorization header is malformed; the region 'us-east-1' is wrong; expecting 'us-west-2' status code: 400, request id: []"
+	testDirectoryName      = "corp.test.com"
+	testDirectoryId        = "d-0123456789"
+)
+
+var TestCases = []TestCase{
+	generateTestCaseOk(testDirectoryId, testDirectoryName, []string{"10.0.0.0", "10.0.1.0"}),
+	generateTestCaseFail(testDirectoryId, testDirectoryName, []string{"10.0.0.2", "10.0.1.2"}),
+}
+
+var logger = log.NewMockLog()
+
+func generateTestCaseOk(id string, name string, ipAddress []string) TestCase {
+	out := contracts.PluginOutput{
+		Stdout:   "",
+		Stderr:   "",
+		ExitCode: 0,
+		Status:   "Success",
+	}
+
+	return TestCase{
+		Input:  generateDomainJoinPluginInput(id, name, ipAddress),
+		Output: out,
+		mark:   true,
+	}
+}
+
+func generateTestCaseFail(id string, name string, ipAddress []string) TestCase {
+	out := contracts.PluginOutput{
+		Stdout:   "",
+		Stderr:   "",
+		ExitCode: 1,
+		Status:   "Failed",
+	}
+
+	return TestCase{
+		Input:  generateDomainJoinPluginInput(id, name, ipAddress),
+		Output: out,
+		mark:   false,
+	}
+}
+
+func generateDomainJoinPluginInput(id string, name string, ipAddress []string) DomainJoinPluginInput {
+	return DomainJoinPluginInput{
+		DirectoryId:    id,
+		DirectoryName:  name,
+		DnsIpAddresses: ipAddress,
+	}
+}
@@ -28,69 +28,6 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-type TestCase struct {
-	Input          DomainJoinPluginInput
-	Output         contracts.PluginOutput
-	ExecuterErrors []error
-	mark           bool
-}
-
-const (
-	orchestrationDirectory = "OrchesDir"
-	s3BucketName           = "bucket"
-	s3KeyPrefix            = "key"
-	testInstanceID         = "i-12345678"
-	bucketRegionErrorMsg   = "AuthorizationHeaderMalformed: The authorization header is malformed; the region 'us-east-1' is wrong; expecting 'us-west-2' status code: 400, request id: []"
-	testDirectoryName      = "corp.test.com"
-	testDirectoryId        = "d-0123456789"
-)
-
-var TestCases = []TestCase{
-	generateTestCaseOk(testDirectoryId, testDirectoryName, []string{"10.0.0.0", "10.0.1.0"}),
-	generateTestCaseFail(testDirectoryId, testDirectoryName, []string{"10.0.0.2", "10.0.1.2"}),
-}
-
-var logger = log.NewMockLog()
-
-func generateTestCaseOk(id string, name string, ipAddress []string) TestCase {
-
-	var out = contracts.PluginOutput{
-		Stdout:   "",
-		Stderr:   "",
-		ExitCode: 0,
-		Status:   "Success",
-	}
-
-	return TestCase{
-		Input:  generateDomainJoinPluginInput(id, name, ipAddress),
-		Output: contracts.PluginOutput{out},
-		mark:   true,
-	}
-}
-
-func generateTestCaseFail(id string, name string, ipAddress []string) TestCase {
-	var out = contracts.PluginOutput{
-		Stdout:   "",
-		Stderr:   "",
-		ExitCode: 1,
-		Status:   "Failed",
-	}
-
-	return TestCase{
-		Input:  generateDomainJoinPluginInput(id, name, ipAddress),
-		Output: contracts.PluginOutput{out},
-		mark:   false,
-	}
-}
-
-func generateDomainJoinPluginInput(id string, name string, ipAddress []string) DomainJoinPluginInput {
-	return DomainJoinPluginInput{
-		DirectoryId:    id,
-		DirectoryName:  name,
-		DnsIpAddresses: ipAddress,
-	}
-}
-
 // TestRunCommands tests the runCommands and runCommandsRawInput methods, which run one set of commands.
 func TestRunCommands(t *testing.T) {
 	for _, testCase := range TestCases {
@@ -118,9 +55,10 @@ func testRunCommands(t *testing.T, testCase TestCase, rawInput bool) {
 	makeDir = func(destinationDir string) (err error) {
 		return nil
 	}
-	makeArgs = func(log log.T, pluginInput DomainJoinPluginInput) (commandArguments string) {
-		return "cmd"
+	getRegion = func() (string, error) {
+		return "us-east-1", nil
 	}
+	joiner = &windowsJoiner{}
 
 	var res contracts.PluginOutput
 	mockCancelFlag := new(task.MockCancelFlag)
@@ -0,0 +1,47 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin
+
+package domainjoin
+
+import (
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+func init() {
+	joiner = &darwinJoiner{}
+}
+
+// darwinJoiner joins an AWS Directory Service directory via dsconfigad, macOS's built-in
+// Active Directory binding tool.
+type darwinJoiner struct{}
+
+// Arguments builds the dsconfigad command line for the join. dsconfigad has no flag for the
+// directory's DNS IP addresses: macOS resolves the domain through the network location's
+// resolver, which the agent does not manage on this platform, so DnsIpAddresses is intentionally
+// not passed through. DirectoryId becomes the AD computer ID and DirectoryOU (if set) places the
+// computer object in that OU.
+func (j *darwinJoiner) Arguments(log log.T, pluginInput DomainJoinPluginInput) (commandArguments string, err error) {
+	var command []string
+	command = append(command, "dsconfigad", "-add", pluginInput.DirectoryName)
+	command = append(command, "-computerid", pluginInput.DirectoryId)
+	if pluginInput.DirectoryOU != "" {
+		command = append(command, "-ou", pluginInput.DirectoryOU)
+	}
+	command = append(command, "-force")
+
+	return strings.Join(command, " "), nil
+}
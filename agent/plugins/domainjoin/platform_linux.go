@@ -0,0 +1,143 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build linux
+
+package domainjoin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+func init() {
+	joiner = &linuxJoiner{distro: detectDistro}
+}
+
+// linuxDistroFamily identifies the package/identity tooling available on the instance.
+type linuxDistroFamily int
+
+const (
+	// rhelFamily covers RHEL/CentOS/Amazon Linux, which join via realmd+adcli.
+	rhelFamily linuxDistroFamily = iota
+	// debianFamily covers Ubuntu/Debian, which join via sssd+net ads.
+	debianFamily
+)
+
+// resolvConfPath is where the DNS resolver configuration for the directory is written.
+const resolvConfPath = "/etc/resolv.conf"
+
+// linuxJoiner joins an AWS Directory Service directory via realmd/adcli on RHEL-family systems
+// and sssd/net ads on Debian-family systems, configuring the DNS resolver, the OU the computer
+// object is placed in, and the resulting host keytab along the way.
+type linuxJoiner struct {
+	distro func() (linuxDistroFamily, error)
+}
+
+// Arguments builds the shell command that performs the join. Unlike Windows, a single binary
+// isn't invoked; instead a short pipeline joins and places the instance in its OU, writing a
+// keytab for the host principal. It has no side effects; see ConfigureResolver for the DNS step
+// runCommands sequences ahead of this command.
+func (j *linuxJoiner) Arguments(log log.T, pluginInput DomainJoinPluginInput) (commandArguments string, err error) {
+	family, err := j.distro()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect Linux distribution: %s", err)
+	}
+
+	switch family {
+	case rhelFamily:
+		return rhelJoinCommand(pluginInput), nil
+	case debianFamily:
+		return debianJoinCommand(pluginInput), nil
+	default:
+		return "", fmt.Errorf("unsupported Linux distribution family")
+	}
+}
+
+// ConfigureResolver points the instance's DNS resolver at the directory's DNS IP addresses so the
+// join commands built by Arguments can resolve the domain and, on Debian-family systems, discover
+// its domain controllers via SRV records. runCommands calls this as its own step ahead of
+// Arguments' command, rather than Arguments triggering it as a side effect.
+func (j *linuxJoiner) ConfigureResolver(pluginInput DomainJoinPluginInput) error {
+	return configureResolver(pluginInput.DnsIpAddresses, pluginInput.DirectoryName)
+}
+
+// rhelJoinCommand joins via realmd/adcli. realm join's sssd client software writes the host
+// keytab to /etc/krb5.keytab automatically as part of the join; --computer-ou places the
+// computer object in pluginInput.DirectoryOU when one is given.
+func rhelJoinCommand(pluginInput DomainJoinPluginInput) string {
+	var command []string
+	command = append(command, "realm", "join")
+	command = append(command, "--install=/")
+	command = append(command, "--client-software=sssd")
+	if pluginInput.DirectoryOU != "" {
+		command = append(command, "--computer-ou", pluginInput.DirectoryOU)
+	}
+	command = append(command, pluginInput.DirectoryName)
+
+	return strings.Join(command, " ")
+}
+
+// debianJoinCommand joins via sssd+net ads, which is the supported path on Debian-family systems.
+// net ads join takes the realm/workgroup to join from smb.conf, not from the command line, and
+// discovers a domain controller to contact via the DNS SRV records ConfigureResolver points it at
+// (net ads join's -S flag is for pinning a specific server host, which is not something
+// pluginInput carries, so it is intentionally omitted here rather than misused for the directory
+// name). createcomputer places the computer object in pluginInput.DirectoryOU when one is given,
+// and the trailing "net ads keytab create" writes the host keytab to /etc/krb5.keytab, which net
+// ads join does not do on its own.
+func debianJoinCommand(pluginInput DomainJoinPluginInput) string {
+	var command []string
+	command = append(command, "net", "ads", "join")
+	if pluginInput.DirectoryOU != "" {
+		command = append(command, "createcomputer="+pluginInput.DirectoryOU)
+	}
+	command = append(command, "-k")
+
+	return strings.Join(command, " ") + " && net ads keytab create -k"
+}
+
+// configureResolver points the instance's DNS resolver at the directory's DNS IP addresses so
+// that the join commands above can resolve the domain. It is overridden in tests.
+var configureResolver = writeResolverConfig
+
+// writeResolverConfig is the real implementation of configureResolver.
+func writeResolverConfig(dnsIpAddresses []string, directoryName string) error {
+	var resolvConf strings.Builder
+	resolvConf.WriteString(fmt.Sprintf("search %s\n", directoryName))
+	for _, ip := range dnsIpAddresses {
+		resolvConf.WriteString(fmt.Sprintf("nameserver %s\n", ip))
+	}
+
+	return ioutil.WriteFile(resolvConfPath, []byte(resolvConf.String()), 0644)
+}
+
+// detectDistro inspects /etc/os-release to decide which tooling to use for the join.
+func detectDistro() (linuxDistroFamily, error) {
+	osRelease, err := ioutil.ReadFile("/etc/os-release")
+	if err != nil {
+		return rhelFamily, fmt.Errorf("failed to read /etc/os-release: %s", err)
+	}
+
+	contents := strings.ToLower(string(osRelease))
+	for _, id := range []string{"ubuntu", "debian"} {
+		if strings.Contains(contents, id) {
+			return debianFamily, nil
+		}
+	}
+
+	return rhelFamily, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+package domainjoin
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// ec2ConfigDomainJoinExe is the bundled helper that performs the actual domain join on Windows.
+const ec2ConfigDomainJoinExe = "./Ec2Config.DomainJoin.exe"
+
+func init() {
+	joiner = &windowsJoiner{}
+	shellCommand = func(cmd string) *exec.Cmd {
+		return exec.Command("cmd", "/C", cmd)
+	}
+}
+
+// windowsJoiner joins an AWS Directory Service directory via Ec2Config.DomainJoin.exe.
+type windowsJoiner struct{}
+
+// Arguments builds the Ec2Config.DomainJoin.exe command line.
+func (w *windowsJoiner) Arguments(log log.T, pluginInput DomainJoinPluginInput) (commandArguments string, err error) {
+	return makeArguments(log, pluginInput)
+}
+
+// makeArguments builds up the command for Ec2Config.DomainJoin.exe.
+func makeArguments(log log.T, pluginInput DomainJoinPluginInput) (commandArguments string, err error) {
+	region, regionErr := getRegion()
+	if regionErr != nil {
+		return "", fmt.Errorf("failed to get region: %s", regionErr)
+	}
+
+	var command []string
+	command = append(command, ec2ConfigDomainJoinExe)
+	command = append(command, "--directory-id", pluginInput.DirectoryId)
+	command = append(command, "--directory-name", pluginInput.DirectoryName)
+	command = append(command, "--instance-region", region)
+	if pluginInput.DirectoryOU != "" {
+		command = append(command, "--ou", pluginInput.DirectoryOU)
+	}
+	command = append(command, "--dns-addresses")
+	command = append(command, pluginInput.DnsIpAddresses...)
+
+	return strings.Join(command, " "), nil
+}
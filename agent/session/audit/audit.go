@@ -0,0 +1,87 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package audit implements a tamper-evident, hash-chained audit log of control-channel
+// activity: session start/terminate, plugin start/complete, and reconnects.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Event types recorded by the audit log.
+const (
+	EventSessionStart     = "SessionStart"
+	EventSessionTerminate = "SessionTerminate"
+	EventPluginStart      = "PluginStart"
+	EventPluginComplete   = "PluginComplete"
+	EventReconnect        = "Reconnect"
+)
+
+// GenesisHash is the prev_hash of the first entry written to a fresh audit log.
+const GenesisHash = ""
+
+// Entry is one tamper-evident record in the audit log.
+type Entry struct {
+	// SequenceNumber is the 0-indexed position of this entry in the log.
+	SequenceNumber int64 `json:"sequence_number"`
+	// Timestamp is the Unix milliseconds at which the event was recorded.
+	Timestamp int64 `json:"timestamp"`
+	// EventType is one of the Event* constants above.
+	EventType string `json:"event_type"`
+	// SessionId identifies the Session Manager session the event belongs to, if any.
+	SessionId string `json:"session_id,omitempty"`
+	// PluginId identifies the plugin the event belongs to, if any.
+	PluginId string `json:"plugin_id,omitempty"`
+	// Details carries event-specific, human-readable context.
+	Details map[string]string `json:"details,omitempty"`
+	// PrevHash is the Hash of the preceding entry (GenesisHash for the first entry).
+	PrevHash string `json:"prev_hash"`
+	// Hash is SHA-256(PrevHash || canonicalJSON(entry with Hash cleared)), hex-encoded.
+	Hash string `json:"hash"`
+}
+
+// canonicalPayload returns the deterministic byte representation of the entry that is hashed,
+// i.e. the entry with its own Hash field cleared so the hash cannot reference itself.
+func canonicalPayload(e Entry) ([]byte, error) {
+	e.Hash = ""
+	return json.Marshal(e)
+}
+
+// computeHash returns the hex-encoded SHA-256 of (prevHash || canonicalJSON(entry)).
+func computeHash(e Entry) (string, error) {
+	payload, err := canonicalPayload(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize audit entry: %s", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AuditSink persists audit Entries. Implementations must be safe for concurrent use.
+type AuditSink interface {
+	// Record appends one event to the audit log, chaining its hash to the previous entry, and
+	// returns the entry that was written (including its computed Hash).
+	Record(eventType string, sessionId string, pluginId string, details map[string]string) (Entry, error)
+	// HeadHash returns the Hash of the most recently written entry (GenesisHash if none yet),
+	// suitable for periodic external anchoring.
+	HeadHash() string
+	// Close releases any resources held by the sink.
+	Close() error
+}
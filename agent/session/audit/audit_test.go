@@ -0,0 +1,154 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMemorySinkChainsHashes verifies that each recorded entry's PrevHash matches the
+// previous entry's Hash, and that the first entry chains from GenesisHash.
+func TestMemorySinkChainsHashes(t *testing.T) {
+	sink := NewMemorySink()
+
+	first, err := sink.Record(EventSessionStart, "session-1", "", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, GenesisHash, first.PrevHash)
+
+	second, err := sink.Record(EventPluginStart, "session-1", "plugin-1", map[string]string{"foo": "bar"})
+	assert.Nil(t, err)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.Equal(t, second.Hash, sink.HeadHash())
+}
+
+// TestVerifyChainDetectsValidLog verifies a correctly-chained log round-trips through VerifyChain.
+func TestVerifyChainDetectsValidLog(t *testing.T) {
+	sink := NewMemorySink()
+	sink.Record(EventSessionStart, "session-1", "", nil)
+	sink.Record(EventPluginStart, "session-1", "plugin-1", nil)
+	sink.Record(EventPluginComplete, "session-1", "plugin-1", nil)
+
+	var buf bytes.Buffer
+	for _, entry := range sink.Entries() {
+		line, err := json.Marshal(entry)
+		assert.Nil(t, err)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	result, err := VerifyChain(&buf)
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+	assert.EqualValues(t, 3, result.EntriesChecked)
+}
+
+// TestVerifyChainDetectsTampering verifies that mutating a middle entry's details is caught as
+// a broken hash at that entry.
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	sink := NewMemorySink()
+	sink.Record(EventSessionStart, "session-1", "", nil)
+	sink.Record(EventPluginStart, "session-1", "plugin-1", map[string]string{"command": "echo hi"})
+	sink.Record(EventPluginComplete, "session-1", "plugin-1", nil)
+
+	lines := make([]string, 0, 3)
+	for _, entry := range sink.Entries() {
+		line, err := json.Marshal(entry)
+		assert.Nil(t, err)
+		lines = append(lines, string(line))
+	}
+
+	// Tamper with the recorded command in the second entry without recomputing its hash.
+	lines[1] = strings.Replace(lines[1], "echo hi", "rm -rf /", 1)
+
+	result, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.EqualValues(t, 1, result.BrokenAt)
+}
+
+// writeSegment JSON-encodes entry and writes it as the sole line of a new file at path,
+// mirroring what FileSink leaves behind in each segment it rolls to.
+func writeSegment(t *testing.T, path string, entry Entry) {
+	line, err := json.Marshal(entry)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(path, append(line, '\n'), 0644))
+}
+
+// chainedEntry builds an Entry continuing from prevHash with a correctly computed Hash, the way
+// FileSink.Record does, so tests can hand-construct a chain that spans rolled segments without
+// depending on FileSink's own byte-size roll threshold.
+func chainedEntry(t *testing.T, seq int64, eventType string, details map[string]string, prevHash string) Entry {
+	entry := Entry{SequenceNumber: seq, EventType: eventType, Details: details, PrevHash: prevHash}
+	hash, err := computeHash(entry)
+	assert.Nil(t, err)
+	entry.Hash = hash
+	return entry
+}
+
+// TestVerifyRolledChainAcceptsUntamperedRolledSegments verifies that a log spanning multiple
+// rolled segments, whose first entry in each later segment chains from the previous segment's
+// last hash rather than from GenesisHash (exactly what FileSink.roll produces), is reported valid
+// when walked as a whole.
+func TestVerifyRolledChainAcceptsUntamperedRolledSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit-verify-rolled")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := chainedEntry(t, 0, EventSessionStart, nil, GenesisHash)
+	second := chainedEntry(t, 1, EventPluginStart, nil, first.Hash)
+	third := chainedEntry(t, 2, EventPluginComplete, nil, second.Hash)
+
+	writeSegment(t, filepath.Join(dir, "session-audit.log"), first)
+	writeSegment(t, filepath.Join(dir, "session-audit.log.1"), second)
+	writeSegment(t, filepath.Join(dir, "session-audit.log.2"), third)
+
+	result, err := VerifyRolledChain(dir, "session-audit.log")
+	assert.Nil(t, err)
+	assert.True(t, result.Valid, "reason: %s, broken file: %s", result.Reason, result.BrokenFile)
+	assert.EqualValues(t, 3, result.EntriesChecked)
+}
+
+// TestVerifyRolledChainReportsTamperedSegment verifies that tampering with a later rolled segment
+// is still caught, and that the broken segment's path is reported.
+func TestVerifyRolledChainReportsTamperedSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit-verify-rolled-tampered")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := chainedEntry(t, 0, EventSessionStart, nil, GenesisHash)
+	second := chainedEntry(t, 1, EventPluginStart, map[string]string{"command": "echo hi"}, first.Hash)
+
+	writeSegment(t, filepath.Join(dir, "session-audit.log"), first)
+	tamperedPath := filepath.Join(dir, "session-audit.log.1")
+	writeSegment(t, tamperedPath, second)
+
+	contents, err := ioutil.ReadFile(tamperedPath)
+	assert.Nil(t, err)
+	tampered := strings.Replace(string(contents), "echo hi", "rm -rf /", 1)
+	assert.Nil(t, ioutil.WriteFile(tamperedPath, []byte(tampered), 0644))
+
+	result, err := VerifyRolledChain(dir, "session-audit.log")
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, tamperedPath, result.BrokenFile)
+	assert.EqualValues(t, 1, result.BrokenAt)
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Command verifyaudit walks a session audit log and reports the first broken link in its
+// hash chain, if any. FileSink rolls to baseName.1, baseName.2, ... once a segment exceeds its
+// size limit, continuing the same hash chain across the boundary, so verifyaudit walks every
+// rolled segment it finds in order rather than just the one named by -file.
+//
+//	verifyaudit -file /var/log/amazon/ssm/session-audit.log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/session/audit"
+)
+
+func main() {
+	filePath := flag.String("file", "", "path to the base audit log file to verify (its rolled .1, .2, ... segments are verified too)")
+	flag.Parse()
+
+	if *filePath == "" {
+		fmt.Fprintln(os.Stderr, "usage: verifyaudit -file <path to audit log>")
+		os.Exit(2)
+	}
+
+	dir := filepath.Dir(*filePath)
+	baseName := filepath.Base(*filePath)
+
+	result, err := audit.VerifyRolledChain(dir, baseName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to verify %s: %s\n", *filePath, err)
+		os.Exit(1)
+	}
+
+	if result.Valid {
+		fmt.Printf("OK: %d entries verified, chain intact\n", result.EntriesChecked)
+		return
+	}
+
+	fmt.Printf("BROKEN: first bad link in %s at sequence_number=%d (%d entries verified before it): %s\n",
+		result.BrokenFile, result.BrokenAt, result.EntriesChecked, result.Reason)
+	os.Exit(1)
+}
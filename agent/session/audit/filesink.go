@@ -0,0 +1,162 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultMaxFileBytes is the size at which FileSink rolls to a new numbered log file.
+const defaultMaxFileBytes = 10 * 1024 * 1024
+
+// FileSink is an AuditSink that writes hash-chained entries as JSON-lines to a rolling file on disk.
+type FileSink struct {
+	mu        sync.Mutex
+	dir       string
+	baseName  string
+	maxBytes  int64
+	file      *os.File
+	size      int64
+	seq       int64
+	headHash  string
+	fileIndex int
+}
+
+// NewFileSink creates a FileSink that writes to path, rolling to path.1, path.2, ... once the
+// active file exceeds maxBytes (defaultMaxFileBytes if maxBytes <= 0).
+func NewFileSink(path string, maxBytes int64) (*FileSink, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFileBytes
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory %s: %s", dir, err)
+	}
+
+	s := &FileSink{
+		dir:      dir,
+		baseName: filepath.Base(path),
+		maxBytes: maxBytes,
+		headHash: GenesisHash,
+	}
+
+	if err := s.openCurrentFile(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// currentPath returns the path of the file currently being written to.
+func (s *FileSink) currentPath() string {
+	if s.fileIndex == 0 {
+		return filepath.Join(s.dir, s.baseName)
+	}
+	return filepath.Join(s.dir, fmt.Sprintf("%s.%d", s.baseName, s.fileIndex))
+}
+
+// openCurrentFile opens (creating if necessary) the file at currentPath for appending.
+func (s *FileSink) openCurrentFile() error {
+	f, err := os.OpenFile(s.currentPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %s", s.currentPath(), err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log %s: %s", s.currentPath(), err)
+	}
+
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// Record appends one event to the audit log.
+func (s *FileSink) Record(eventType string, sessionId string, pluginId string, details map[string]string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := Entry{
+		SequenceNumber: s.seq,
+		Timestamp:      time.Now().UnixNano() / int64(time.Millisecond),
+		EventType:      eventType,
+		SessionId:      sessionId,
+		PluginId:       pluginId,
+		Details:        details,
+		PrevHash:       s.headHash,
+	}
+
+	hash, err := computeHash(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to marshal audit entry: %s", err)
+	}
+	line = append(line, '\n')
+
+	if s.size+int64(len(line)) > s.maxBytes {
+		if err = s.roll(); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to write audit entry: %s", err)
+	}
+
+	s.size += int64(n)
+	s.seq++
+	s.headHash = entry.Hash
+
+	return entry, nil
+}
+
+// roll closes the current file and starts a new, numbered one.
+func (s *FileSink) roll() error {
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.fileIndex++
+	return s.openCurrentFile()
+}
+
+// HeadHash returns the Hash of the most recently written entry.
+func (s *FileSink) HeadHash() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headHash
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package audit
+
+import "sync"
+
+// MemorySink is an in-memory AuditSink for tests that substitutes for FileSink so unit tests
+// don't need to touch disk.
+type MemorySink struct {
+	mu       sync.Mutex
+	entries  []Entry
+	headHash string
+}
+
+// NewMemorySink creates an empty MemorySink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{headHash: GenesisHash}
+}
+
+// Record appends one event to the in-memory log.
+func (s *MemorySink) Record(eventType string, sessionId string, pluginId string, details map[string]string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := Entry{
+		SequenceNumber: int64(len(s.entries)),
+		EventType:      eventType,
+		SessionId:      sessionId,
+		PluginId:       pluginId,
+		Details:        details,
+		PrevHash:       s.headHash,
+	}
+
+	hash, err := computeHash(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Hash = hash
+
+	s.entries = append(s.entries, entry)
+	s.headHash = hash
+	return entry, nil
+}
+
+// HeadHash returns the Hash of the most recently written entry.
+func (s *MemorySink) HeadHash() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headHash
+}
+
+// Close is a no-op for MemorySink.
+func (s *MemorySink) Close() error {
+	return nil
+}
+
+// Entries returns a copy of the entries recorded so far, in order.
+func (s *MemorySink) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Entry, len(s.entries))
+	copy(out, s.entries)
+	return out
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// VerifyResult reports the outcome of walking an audit log's hash chain.
+type VerifyResult struct {
+	// EntriesChecked is how many entries were read before verification stopped.
+	EntriesChecked int64
+	// Valid is true if every entry's hash chained correctly to the end of the log.
+	Valid bool
+	// BrokenAt is the SequenceNumber of the first entry whose hash did not chain correctly
+	// (-1 if Valid is true).
+	BrokenAt int64
+	// BrokenFile is the path of the rolled segment BrokenAt was found in, set only by
+	// VerifyRolledChain (empty for a single-reader VerifyChain call or when Valid is true).
+	BrokenFile string
+	// Reason describes why BrokenAt failed verification (empty if Valid is true).
+	Reason string
+}
+
+// VerifyChain reads newline-delimited audit Entries from r and verifies that each entry's
+// PrevHash matches the previous entry's Hash and that each entry's Hash is correctly computed.
+// It stops and reports the first broken link it finds. r is assumed to hold a log whose first
+// entry chains from GenesisHash; to verify a segment that FileSink rolled to (and so continues an
+// earlier file's chain), use VerifyRolledChain instead.
+func VerifyChain(r io.Reader) (VerifyResult, error) {
+	result, _, err := verifyChain(r, GenesisHash, 0)
+	return result, err
+}
+
+// verifyChain is the shared implementation behind VerifyChain and VerifyRolledChain. It returns
+// the hash chained entries ended on, so callers walking multiple rolled segments can carry it
+// into the next segment's starting prevHash. startSeq is the number of entries in earlier
+// segments, used as the base of BrokenAt for a line that is not valid JSON.
+func verifyChain(r io.Reader, startPrevHash string, startSeq int64) (VerifyResult, string, error) {
+	scanner := bufio.NewScanner(r)
+	// Audit entries can carry arbitrarily large Details payloads; grow the buffer accordingly.
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	prevHash := startPrevHash
+	var checked int64
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return VerifyResult{EntriesChecked: checked, Valid: false, BrokenAt: startSeq + checked, Reason: fmt.Sprintf("invalid JSON: %s", err)}, "", nil
+		}
+
+		if entry.PrevHash != prevHash {
+			return VerifyResult{EntriesChecked: checked, Valid: false, BrokenAt: entry.SequenceNumber, Reason: "prev_hash does not match the previous entry's hash"}, "", nil
+		}
+
+		expectedHash, err := computeHash(entry)
+		if err != nil {
+			return VerifyResult{}, "", err
+		}
+		if expectedHash != entry.Hash {
+			return VerifyResult{EntriesChecked: checked, Valid: false, BrokenAt: entry.SequenceNumber, Reason: "hash does not match the entry's contents"}, "", nil
+		}
+
+		prevHash = entry.Hash
+		checked++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return VerifyResult{}, "", fmt.Errorf("failed to read audit log: %s", err)
+	}
+
+	return VerifyResult{EntriesChecked: checked, Valid: true, BrokenAt: -1}, prevHash, nil
+}
+
+// segmentPath returns the path FileSink gives its index'th segment: baseName itself for index 0,
+// "baseName.N" for index N>0 (see FileSink.currentPath).
+func segmentPath(dir string, baseName string, index int) string {
+	if index == 0 {
+		return filepath.Join(dir, baseName)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%d", baseName, index))
+}
+
+// VerifyRolledChain verifies every segment a FileSink rolled for baseName under dir, in order:
+// baseName, baseName.1, baseName.2, ... for as long as the next segment exists. The hash chain
+// (and EntriesChecked count) carries across segment boundaries the same way FileSink.roll
+// continues it in-process, so an untampered rolled log reports Valid even though each individual
+// segment's first entry chains from the previous segment's last hash rather than from GenesisHash.
+func VerifyRolledChain(dir string, baseName string) (VerifyResult, error) {
+	prevHash := GenesisHash
+	var totalChecked int64
+
+	for index := 0; ; index++ {
+		path := segmentPath(dir, baseName, index)
+
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			if index == 0 {
+				return VerifyResult{}, fmt.Errorf("no audit log found at %s", path)
+			}
+			break
+		}
+		if err != nil {
+			return VerifyResult{}, fmt.Errorf("failed to open %s: %s", path, err)
+		}
+
+		result, headHash, err := verifyChain(f, prevHash, totalChecked)
+		f.Close()
+		if err != nil {
+			return VerifyResult{}, err
+		}
+
+		result.EntriesChecked += totalChecked
+		if !result.Valid {
+			result.BrokenFile = path
+			return result, nil
+		}
+
+		totalChecked = result.EntriesChecked
+		prevHash = headHash
+	}
+
+	return VerifyResult{EntriesChecked: totalChecked, Valid: true, BrokenAt: -1}, nil
+}
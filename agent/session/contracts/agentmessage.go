@@ -0,0 +1,78 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts defines the structures sent to and from the message gateway service.
+package contracts
+
+import (
+	"encoding/json"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/twinj/uuid"
+)
+
+// Message types understood by the message gateway service.
+const (
+	// TaskCompleteMessage indicates a plugin (or document) has finished running.
+	TaskCompleteMessage = "agent_task_complete"
+	// TaskProgressMessage carries an intermediate chunk of a still-running plugin's output.
+	TaskProgressMessage = "agent_task_progress"
+)
+
+// AgentMessage is the wire format for every message the agent exchanges with the control channel.
+type AgentMessage struct {
+	MessageType    string
+	SchemaVersion  uint32
+	CreatedDate    uint64
+	SequenceNumber int64
+	Flags          uint64
+	MessageId      uuid.UUID
+	Payload        []byte
+}
+
+// Serialize marshals an AgentMessage to the byte slice sent over the control channel's websocket.
+func (a *AgentMessage) Serialize(log log.T) (result []byte, err error) {
+	result, err = json.Marshal(a)
+	if err != nil {
+		log.Errorf("Cannot serialize AgentMessage %v, err: %s", a, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// AgentTaskCompletePayload is the payload of a TaskCompleteMessage.
+type AgentTaskCompletePayload struct {
+	SchemaVersion    int
+	TaskId           string
+	Topic            string
+	FinalTaskStatus  string
+	IsRoutingFailure bool
+	AwsAccountId     string
+	InstanceId       string
+	Output           string
+	// LastProgressSequence is the sequence number of the last AgentTaskProgress message sent for
+	// this plugin (0 if none was sent), so the service can detect a gap in the streamed output.
+	LastProgressSequence int64
+}
+
+// AgentTaskProgressPayload is the payload of a TaskProgressMessage: a bounded, sequence-numbered
+// chunk of a plugin's stdout/stderr, sent while the plugin is still running.
+type AgentTaskProgressPayload struct {
+	SchemaVersion int
+	TaskId        string
+	Topic         string
+	InstanceId    string
+	Output        string
+	Sequence      int64
+	Final         bool
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package controlchannel implements control channel for MGS connection.
+package controlchannel
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/session/service"
+	"github.com/gorilla/websocket"
+)
+
+// pingWriteTimeout bounds how long WriteControl may block sending a ping frame.
+const pingWriteTimeout = 5 * time.Second
+
+// IControlChannel is the interface for control channel.
+type IControlChannel interface {
+	Initialize(context context.T, mgsService service.Service, processor processor.Processor, instanceId string)
+	SetWebSocket(context context.T, mgsService service.Service, processor processor.Processor, instanceId string) error
+	Open(log log.T) error
+	Close(log log.T) error
+	SendMessage(log log.T, input []byte, inputType int) error
+}
+
+// ReconnectObservable is implemented by control/data channels that can notify a caller every
+// time the reconnect manager re-establishes the connection, e.g. for audit logging.
+type ReconnectObservable interface {
+	RegisterReconnectObserver(observer func())
+}
+
+// ControlChannel holds the websocket connection to the message gateway service along
+// with the state needed to keep that connection alive for the lifetime of the agent.
+type ControlChannel struct {
+	context    context.T
+	mgsService service.Service
+	processor  processor.Processor
+	instanceId string
+	channelId  string
+	streamUrl  string
+
+	wsMu      sync.Mutex
+	wsChannel *websocket.Conn
+
+	reconnectMgr *reconnectManager
+}
+
+// RegisterReconnectObserver registers observer to be called every time the control channel
+// successfully reconnects after a disconnect.
+func (c *ControlChannel) RegisterReconnectObserver(observer func()) {
+	c.reconnectMgr.RegisterObserver(observer)
+}
+
+// Initialize populates the ControlChannel with the objects it needs to establish and maintain a connection.
+func (c *ControlChannel) Initialize(context context.T, mgsService service.Service, processor processor.Processor, instanceId string) {
+	c.context = context
+	c.mgsService = mgsService
+	c.processor = processor
+	c.instanceId = instanceId
+	c.reconnectMgr = newReconnectManager(context, newBackoffConfig(context.AppConfig().Mgs), c.reopen, c.sendPing)
+}
+
+// SetWebSocket asks the message gateway service for a fresh control channel stream URL and
+// records it for reopen to dial.
+func (c *ControlChannel) SetWebSocket(context context.T, mgsService service.Service, processor processor.Processor, instanceId string) (err error) {
+	channelId, streamUrl, err := mgsService.CreateControlChannel(context.Log(), instanceId)
+	if err != nil {
+		return fmt.Errorf("failed to create control channel: %s", err)
+	}
+
+	c.channelId = channelId
+	c.streamUrl = streamUrl
+	return nil
+}
+
+// Open opens the websocket connection and starts the health-check/reconnect loop that keeps it alive.
+func (c *ControlChannel) Open(log log.T) (err error) {
+	if err = c.reopen(log); err != nil {
+		return err
+	}
+	c.reconnectMgr.Start(log)
+	return nil
+}
+
+// Close closes the ControlChannel and stops the reconnect manager.
+func (c *ControlChannel) Close(log log.T) (err error) {
+	if c.reconnectMgr != nil {
+		c.reconnectMgr.Stop()
+	}
+
+	c.wsMu.Lock()
+	defer c.wsMu.Unlock()
+	if c.wsChannel != nil {
+		err = c.wsChannel.Close()
+		c.wsChannel = nil
+		return err
+	}
+	return nil
+}
+
+// SendMessage sends a message over the control channel's websocket.
+func (c *ControlChannel) SendMessage(log log.T, input []byte, inputType int) (err error) {
+	c.wsMu.Lock()
+	conn := c.wsChannel
+	c.wsMu.Unlock()
+
+	if conn == nil {
+		return errNotConnected
+	}
+	return conn.WriteMessage(inputType, input)
+}
+
+// sendPing sends a websocket ping control frame on the live connection; the peer's reply is
+// delivered to the pong handler registered in reopen, which notifies the reconnect manager.
+func (c *ControlChannel) sendPing(log log.T) error {
+	c.wsMu.Lock()
+	conn := c.wsChannel
+	c.wsMu.Unlock()
+
+	if conn == nil {
+		return errNotConnected
+	}
+	return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWriteTimeout))
+}
+
+// reopen tears down any existing websocket and establishes a new one. It is called both by Open
+// and by the reconnect manager whenever the connection needs to be re-established.
+func (c *ControlChannel) reopen(log log.T) error {
+	c.wsMu.Lock()
+	if c.wsChannel != nil {
+		c.wsChannel.Close()
+		c.wsChannel = nil
+	}
+	c.wsMu.Unlock()
+
+	if err := c.SetWebSocket(c.context, c.mgsService, c.processor, c.instanceId); err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(c.streamUrl, http.Header{})
+	if err != nil {
+		return fmt.Errorf("failed to dial control channel websocket: %s", err)
+	}
+
+	conn.SetPongHandler(func(string) error {
+		c.reconnectMgr.NotifyPong()
+		return nil
+	})
+
+	c.wsMu.Lock()
+	c.wsChannel = conn
+	c.wsMu.Unlock()
+
+	go c.readPump(log, conn)
+
+	return nil
+}
+
+// readPump reads incoming frames off conn until it errors (the peer closed the connection, or the
+// connection dropped), then notifies the reconnect manager so it can re-establish the connection.
+// Each call to reopen starts a new readPump for its own connection; if conn has since been replaced
+// by the time the read fails (reopen already dialed a new one, or Close tore it down on purpose),
+// that replacement is already being handled elsewhere, so this stale readPump is a no-op.
+func (c *ControlChannel) readPump(log log.T, conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			c.wsMu.Lock()
+			isCurrent := c.wsChannel == conn
+			c.wsMu.Unlock()
+
+			if isCurrent {
+				log.Warnf("control channel read error, reconnecting: %s", err)
+				c.reconnectMgr.NotifyDisconnected(log)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package controlchannel
+
+import "sync/atomic"
+
+// Metrics tracks reconnect behaviour for the control/data channels so health dashboards and
+// diagnostics logging can report on connection stability without reaching into channel internals.
+type Metrics struct {
+	reconnectAttempts int64
+	connectionState   int32
+}
+
+// globalMetrics is shared by every ControlChannel/data channel reconnectManager in the process.
+var globalMetrics = &Metrics{}
+
+// IncrementReconnectAttempts records one more reconnect attempt.
+func (m *Metrics) IncrementReconnectAttempts() {
+	atomic.AddInt64(&m.reconnectAttempts, 1)
+}
+
+// ReconnectAttempts returns the total number of reconnect attempts made so far.
+func (m *Metrics) ReconnectAttempts() int64 {
+	return atomic.LoadInt64(&m.reconnectAttempts)
+}
+
+// SetConnectionState records the current connection state.
+func (m *Metrics) SetConnectionState(state connectionState) {
+	atomic.StoreInt32(&m.connectionState, int32(state))
+}
+
+// IsConnected reports whether the connection is currently considered up.
+func (m *Metrics) IsConnected() bool {
+	return connectionState(atomic.LoadInt32(&m.connectionState)) == stateConnected
+}
+
+// GetMetrics returns the process-wide control/data channel connection metrics.
+func GetMetrics() *Metrics {
+	return globalMetrics
+}
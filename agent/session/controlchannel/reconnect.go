@@ -0,0 +1,268 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package controlchannel
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// connectionState represents the current state of the control channel connection.
+type connectionState int32
+
+const (
+	stateDisconnected connectionState = iota
+	stateConnecting
+	stateConnected
+)
+
+var errNotConnected = errors.New("control channel is not connected")
+
+const (
+	// defaultBaseDelay is used when appconfig.MgsConfig does not specify a backoff base delay.
+	defaultBaseDelay = 1 * time.Second
+	// defaultMaxDelay caps the exponential backoff in the absence of an explicit configuration.
+	defaultMaxDelay = 1 * time.Minute
+	// defaultHealthCheckInterval is how often the reconnect manager pings the control channel.
+	defaultHealthCheckInterval = 30 * time.Second
+	// defaultPongTimeout is how long the reconnect manager waits for a pong after sending a ping
+	// before counting it as missed.
+	defaultPongTimeout = 5 * time.Second
+	// defaultMissedPongLimit is how many consecutive missed pongs trigger a reconnect.
+	defaultMissedPongLimit = 2
+)
+
+// backoffConfig holds the tunables for the exponential backoff used while reconnecting.
+type backoffConfig struct {
+	base              time.Duration
+	max               time.Duration
+	jitterFraction    float64
+	healthCheckPeriod time.Duration
+	pongTimeout       time.Duration
+	missedPongLimit   int
+}
+
+// newBackoffConfig builds a backoffConfig from the agent's MGS configuration, falling back
+// to sane defaults for any value that has not been configured.
+func newBackoffConfig(mgsConfig appconfig.MgsConfig) backoffConfig {
+	cfg := backoffConfig{
+		base:              defaultBaseDelay,
+		max:               defaultMaxDelay,
+		jitterFraction:    0.2,
+		healthCheckPeriod: defaultHealthCheckInterval,
+		pongTimeout:       defaultPongTimeout,
+		missedPongLimit:   defaultMissedPongLimit,
+	}
+
+	if mgsConfig.ControlChannelRetryBaseMillis > 0 {
+		cfg.base = time.Duration(mgsConfig.ControlChannelRetryBaseMillis) * time.Millisecond
+	}
+	if mgsConfig.ControlChannelRetryMaxMillis > 0 {
+		cfg.max = time.Duration(mgsConfig.ControlChannelRetryMaxMillis) * time.Millisecond
+	}
+	if mgsConfig.ControlChannelRetryJitterPercent > 0 {
+		cfg.jitterFraction = float64(mgsConfig.ControlChannelRetryJitterPercent) / 100.0
+	}
+	if mgsConfig.ControlChannelHealthCheckMillis > 0 {
+		cfg.healthCheckPeriod = time.Duration(mgsConfig.ControlChannelHealthCheckMillis) * time.Millisecond
+	}
+
+	return cfg
+}
+
+// nextDelay returns the backoff delay for the given retry attempt (0-indexed), with jitter applied.
+func (b backoffConfig) nextDelay(attempt int) time.Duration {
+	delay := b.base << uint(attempt)
+	if delay <= 0 || delay > b.max {
+		delay = b.max
+	}
+
+	jitter := time.Duration(float64(delay) * b.jitterFraction * rand.Float64())
+	return delay + jitter
+}
+
+// reconnectManager owns the reconnect-with-backoff loop and the health-check goroutine for a
+// ControlChannel. It is shared logic so both the control channel and data channels can reuse it.
+type reconnectManager struct {
+	context context.T
+	backoff backoffConfig
+	reopen  func(log log.T) error
+	ping    func(log log.T) error
+	metrics *Metrics
+
+	state       int32
+	pongPending int32
+	started     int32
+	stopOnce    sync.Once
+	stopChan    chan struct{}
+	doneChan    chan struct{}
+
+	observersMu sync.Mutex
+	observers   []func()
+}
+
+// NotifyPong is called by the control channel's websocket pong handler whenever a pong frame
+// arrives, clearing the outstanding ping started by healthCheckLoop.
+func (m *reconnectManager) NotifyPong() {
+	atomic.StoreInt32(&m.pongPending, 0)
+}
+
+// RegisterObserver registers observer to be called every time reconnectLoop succeeds.
+func (m *reconnectManager) RegisterObserver(observer func()) {
+	m.observersMu.Lock()
+	defer m.observersMu.Unlock()
+	m.observers = append(m.observers, observer)
+}
+
+// notifyObservers invokes every registered observer.
+func (m *reconnectManager) notifyObservers() {
+	m.observersMu.Lock()
+	observers := append([]func(){}, m.observers...)
+	m.observersMu.Unlock()
+
+	for _, observer := range observers {
+		observer()
+	}
+}
+
+// newReconnectManager builds a reconnectManager that re-establishes a connection via reopen
+// whenever the health-check goroutine detects missed pongs or the caller observes a read/close error.
+// ping sends a single websocket ping frame on the live connection; pong receipt is reported back
+// via NotifyPong.
+func newReconnectManager(context context.T, backoff backoffConfig, reopen func(log log.T) error, ping func(log log.T) error) *reconnectManager {
+	return &reconnectManager{
+		context:  context,
+		backoff:  backoff,
+		reopen:   reopen,
+		ping:     ping,
+		metrics:  globalMetrics,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Start marks the connection as established and launches the background health-check goroutine.
+func (m *reconnectManager) Start(log log.T) {
+	atomic.StoreInt32(&m.state, int32(stateConnected))
+	m.metrics.SetConnectionState(stateConnected)
+	atomic.StoreInt32(&m.started, 1)
+	go m.healthCheckLoop(log)
+}
+
+// Stop signals the health-check goroutine to exit and blocks until it has done so. It is safe to
+// call Stop multiple times, e.g. once from ModuleRequestStop and once from a failed reconnect. If
+// Start was never called (e.g. ControlChannel.Open's initial dial failed before Start ran),
+// doneChan is never closed, so Stop only waits on it once healthCheckLoop has actually started.
+func (m *reconnectManager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopChan)
+	})
+	if atomic.LoadInt32(&m.started) == 1 {
+		<-m.doneChan
+	}
+}
+
+// NotifyDisconnected is invoked by callers (e.g. the websocket read loop) when they observe the
+// connection drop outside of the health-check cadence, triggering an immediate reconnect attempt.
+func (m *reconnectManager) NotifyDisconnected(log log.T) {
+	atomic.StoreInt32(&m.state, int32(stateDisconnected))
+	m.metrics.SetConnectionState(stateDisconnected)
+	go m.reconnectLoop(log)
+}
+
+// healthCheckLoop periodically pings the control channel and waits up to backoff.pongTimeout for
+// the pong handler to call NotifyPong; after backoff.missedPongLimit consecutive misses it
+// triggers a reconnect. It exits when Stop is called.
+func (m *reconnectManager) healthCheckLoop(log log.T) {
+	defer close(m.doneChan)
+
+	ticker := time.NewTicker(m.backoff.healthCheckPeriod)
+	defer ticker.Stop()
+
+	missedPongs := 0
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+			if connectionState(atomic.LoadInt32(&m.state)) != stateConnected {
+				continue
+			}
+
+			atomic.StoreInt32(&m.pongPending, 1)
+			if err := m.ping(log); err != nil {
+				log.Warnf("failed to ping control channel: %s", err)
+			}
+
+			select {
+			case <-m.stopChan:
+				return
+			case <-time.After(m.backoff.pongTimeout):
+			}
+
+			if atomic.LoadInt32(&m.pongPending) == 0 {
+				missedPongs = 0
+				continue
+			}
+
+			missedPongs++
+			if missedPongs >= m.backoff.missedPongLimit {
+				log.Warnf("control channel missed %d consecutive pongs, triggering reconnect", missedPongs)
+				missedPongs = 0
+				atomic.StoreInt32(&m.state, int32(stateDisconnected))
+				m.metrics.SetConnectionState(stateDisconnected)
+				m.reconnectLoop(log)
+			}
+		}
+	}
+}
+
+// reconnectLoop retries reopen with exponential backoff and jitter until it succeeds or Stop is called.
+func (m *reconnectManager) reconnectLoop(log log.T) {
+	atomic.StoreInt32(&m.state, int32(stateConnecting))
+	m.metrics.SetConnectionState(stateConnecting)
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-m.stopChan:
+			return
+		default:
+		}
+
+		m.metrics.IncrementReconnectAttempts()
+		if err := m.reopen(log); err == nil {
+			atomic.StoreInt32(&m.state, int32(stateConnected))
+			m.metrics.SetConnectionState(stateConnected)
+			log.Infof("control channel reconnected after %d attempt(s)", attempt+1)
+			m.notifyObservers()
+			return
+		} else {
+			log.Errorf("control channel reconnect attempt %d failed: %s", attempt+1, err)
+		}
+
+		delay := m.backoff.nextDelay(attempt)
+		select {
+		case <-m.stopChan:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package controlchannel
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBackoffConfig returns a backoffConfig with short, deterministic-ish periods so the tests
+// below don't take real minutes to run.
+func testBackoffConfig() backoffConfig {
+	return backoffConfig{
+		base:              10 * time.Millisecond,
+		max:               80 * time.Millisecond,
+		jitterFraction:    0,
+		healthCheckPeriod: 20 * time.Millisecond,
+		pongTimeout:       10 * time.Millisecond,
+		missedPongLimit:   2,
+	}
+}
+
+func TestNextDelay_GrowsExponentially(t *testing.T) {
+	b := testBackoffConfig()
+
+	assert.Equal(t, b.base, b.nextDelay(0))
+	assert.Equal(t, b.base*2, b.nextDelay(1))
+	assert.Equal(t, b.base*4, b.nextDelay(2))
+}
+
+func TestNextDelay_CapsAtMax(t *testing.T) {
+	b := testBackoffConfig()
+
+	for attempt := 10; attempt < 15; attempt++ {
+		assert.Equal(t, b.max, b.nextDelay(attempt))
+	}
+}
+
+func TestNextDelay_AppliesJitterWithinBounds(t *testing.T) {
+	b := testBackoffConfig()
+	b.jitterFraction = 0.5
+
+	for attempt := 0; attempt < 5; attempt++ {
+		delay := b.nextDelay(attempt)
+		base := b.base << uint(attempt)
+		if base <= 0 || base > b.max {
+			base = b.max
+		}
+		assert.True(t, delay >= base, "delay %s should be at least base %s", delay, base)
+		assert.True(t, delay <= base+time.Duration(float64(base)*b.jitterFraction), "delay %s should not exceed base+jitter", delay)
+	}
+}
+
+func TestHealthCheckLoop_MissedPongsTriggerReconnect(t *testing.T) {
+	backoff := testBackoffConfig()
+
+	var pingCount int32
+	var reopenCount int32
+
+	m := newReconnectManager(nil, backoff, func(log log.T) error {
+		atomic.AddInt32(&reopenCount, 1)
+		return nil
+	}, func(log log.T) error {
+		atomic.AddInt32(&pingCount, 1)
+		return nil
+	})
+
+	m.Start(log.NewMockLog())
+	defer m.Stop()
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&reopenCount) >= 1
+	}, 2*time.Second, 5*time.Millisecond, "expected a reconnect after missed pongs, got reopenCount=%d pingCount=%d", reopenCount, pingCount)
+
+	assert.True(t, atomic.LoadInt32(&pingCount) >= int32(backoff.missedPongLimit), "expected at least missedPongLimit pings before reconnect")
+}
+
+func TestHealthCheckLoop_PongReceivedKeepsConnectionAlive(t *testing.T) {
+	backoff := testBackoffConfig()
+
+	var reopenCount int32
+
+	var m *reconnectManager
+	m = newReconnectManager(nil, backoff, func(log log.T) error {
+		atomic.AddInt32(&reopenCount, 1)
+		return nil
+	}, func(log log.T) error {
+		m.NotifyPong()
+		return nil
+	})
+
+	m.Start(log.NewMockLog())
+	defer m.Stop()
+
+	time.Sleep(10 * backoff.healthCheckPeriod)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reopenCount), "a channel that always pongs back should never be forced to reconnect")
+}
+
+func TestStop_ReturnsWhenStartWasNeverCalled(t *testing.T) {
+	backoff := testBackoffConfig()
+
+	m := newReconnectManager(nil, backoff, func(log log.T) error {
+		return nil
+	}, func(log log.T) error {
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop should return immediately when Start was never called, not block on doneChan forever")
+	}
+}
+
+func TestReconnectManager_ObserverNotifiedOnReconnect(t *testing.T) {
+	backoff := testBackoffConfig()
+
+	notified := make(chan struct{}, 1)
+
+	m := newReconnectManager(nil, backoff, func(log log.T) error {
+		return nil
+	}, func(log log.T) error {
+		return nil
+	})
+	m.RegisterObserver(func() {
+		notified <- struct{}{}
+	})
+
+	m.NotifyDisconnected(log.NewMockLog())
+
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected observer to be notified after reconnect")
+	}
+}
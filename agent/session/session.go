@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -29,6 +31,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/amazon-ssm-agent/agent/session/audit"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/controlchannel"
@@ -37,6 +40,10 @@ import (
 	"github.com/twinj/uuid"
 )
 
+// defaultAuditLogPath is where NewSession writes the session activity audit log; NewSession falls
+// back to an in-memory sink if this path can't be opened.
+const defaultAuditLogPath = "/var/log/amazon/ssm/session-audit.log"
+
 // Session encapsulates the logic on configuring, starting and stopping core modules
 type Session struct {
 	context        context.T
@@ -46,6 +53,19 @@ type Session struct {
 	service        service.Service
 	controlChannel controlchannel.IControlChannel
 	processor      processor.Processor
+
+	// sequenceNumber is the monotonically increasing counter stamped on every AgentMessage sent
+	// over the control channel, progress or complete, so the service can detect gaps.
+	sequenceNumber int64
+
+	// progressMu guards progressStreams and lastProgressSeq, both indexed by "sessionId/pluginId".
+	progressMu      sync.Mutex
+	progressStreams map[string]*progressStreamer
+	lastProgressSeq map[string]int64
+
+	// auditSink records tamper-evident audit entries for session start/terminate, plugin
+	// start/complete, and control channel reconnects. Tests can substitute audit.NewMemorySink().
+	auditSink audit.AuditSink
 }
 
 // NewSession gets session core module that manages the web-socket connection between Agent and message gateway service.
@@ -103,17 +123,33 @@ func NewSession(context context.T) *Session {
 
 	controlChannel := &controlchannel.ControlChannel{}
 
+	var auditSink audit.AuditSink
+	if fileSink, auditErr := audit.NewFileSink(defaultAuditLogPath, 0); auditErr != nil {
+		log.Errorf("failed to open session audit log at %s, falling back to in-memory audit sink: %s", defaultAuditLogPath, auditErr)
+		auditSink = audit.NewMemorySink()
+	} else {
+		auditSink = fileSink
+	}
+
 	return &Session{
-		context:        sessionContext,
-		agentConfig:    agentConfig,
-		name:           mgsConfig.SessionServiceName,
-		mgsConfig:      messageGatewayServiceConfig,
-		service:        mgsService,
-		processor:      processor,
-		controlChannel: controlChannel,
+		context:         sessionContext,
+		agentConfig:     agentConfig,
+		name:            mgsConfig.SessionServiceName,
+		mgsConfig:       messageGatewayServiceConfig,
+		service:         mgsService,
+		processor:       processor,
+		controlChannel:  controlChannel,
+		progressStreams: map[string]*progressStreamer{},
+		lastProgressSeq: map[string]int64{},
+		auditSink:       auditSink,
 	}
 }
 
+// nextSequenceNumber returns the next sequence number to stamp on an outgoing AgentMessage.
+func (s *Session) nextSequenceNumber() int64 {
+	return atomic.AddInt64(&s.sequenceNumber, 1)
+}
+
 // ICoreModule implementation
 
 // ModuleName returns the name of module
@@ -135,6 +171,10 @@ func (s *Session) ModuleExecute(context context.T) (err error) {
 
 	instanceId := s.agentConfig.InstanceID
 
+	if _, auditErr := s.auditSink.Record(audit.EventSessionStart, instanceId, "", nil); auditErr != nil {
+		log.Errorf("failed to write session start audit entry: %s", auditErr)
+	}
+
 	resultChan, err := s.processor.Start()
 	if err != nil {
 		log.Errorf("unable to start session document processor: %s", err)
@@ -143,16 +183,31 @@ func (s *Session) ModuleExecute(context context.T) (err error) {
 
 	go s.listenReply(resultChan, instanceId)
 
+	// If the processor supports pushing partial plugin output, stream it as AgentTaskProgress
+	// messages instead of only learning about a plugin's result once it completes.
+	if reporter, ok := s.processor.(progressReporter); ok {
+		reporter.RegisterProgressHandler(s.handlePluginProgress)
+	}
+
 	if err = s.processor.InitialProcessing(); err != nil {
 		log.Errorf("initial processing in EngineProcessor encountered error: %v", err)
 		return
 	}
 
-	// TODO: add retry for create/open controlchannel
 	s.controlChannel.Initialize(s.context, s.service, s.processor, instanceId)
-	if s.controlChannel.SetWebSocket(s.context, s.service, s.processor, instanceId); err != nil {
+	if err = s.controlChannel.SetWebSocket(s.context, s.service, s.processor, instanceId); err != nil {
 		log.Errorf("failed to populate websocket for controlchannel, error %s", err)
 	}
+
+	if observable, ok := s.controlChannel.(controlchannel.ReconnectObservable); ok {
+		observable.RegisterReconnectObserver(func() {
+			if _, auditErr := s.auditSink.Record(audit.EventReconnect, instanceId, "", nil); auditErr != nil {
+				log.Errorf("failed to write reconnect audit entry: %s", auditErr)
+			}
+		})
+	}
+	// Open establishes the connection and, from then on, owns reconnecting on websocket
+	// close/read errors with exponential backoff until ModuleRequestStop closes the channel.
 	if err := s.controlChannel.Open(s.context.Log()); err != nil {
 		log.Errorf("failed to open controlchannel, error %s", err)
 	}
@@ -173,6 +228,13 @@ func (s *Session) ModuleRequestStop(stopType contracts.StopType) (err error) {
 
 	s.processor.Stop(stopType)
 
+	if _, auditErr := s.auditSink.Record(audit.EventSessionTerminate, s.agentConfig.InstanceID, "", nil); auditErr != nil {
+		log.Errorf("failed to write session terminate audit entry: %s", auditErr)
+	}
+	if closeErr := s.auditSink.Close(); closeErr != nil {
+		log.Errorf("failed to close session audit log: %s", closeErr)
+	}
+
 	return nil
 }
 
@@ -189,7 +251,14 @@ func (s *Session) listenReply(resultChan chan contracts.DocumentResult, instance
 			log.Infof("session: %s complete", res.MessageID)
 		}
 
-		msg, err := buildAgentTaskComplete(log, res, instanceId)
+		if res.LastPlugin != "" {
+			if _, auditErr := s.auditSink.Record(audit.EventPluginComplete, res.MessageID, res.LastPlugin, nil); auditErr != nil {
+				log.Errorf("failed to write plugin complete audit entry: %s", auditErr)
+			}
+		}
+
+		lastProgressSeq := s.lastProgressSequenceFor(res.MessageID, res.LastPlugin)
+		msg, err := s.buildAgentTaskComplete(log, res, instanceId, lastProgressSeq)
 		if err != nil {
 			log.Errorf("Cannot build AgentTaskComplete message %s", err)
 			return
@@ -205,8 +274,10 @@ func (s *Session) listenReply(resultChan chan contracts.DocumentResult, instance
 	}
 }
 
-// buildAgentTaskComplete builds AgentTaskComplete message.
-func buildAgentTaskComplete(log log.T, res contracts.DocumentResult, instanceId string) (result []byte, err error) {
+// buildAgentTaskComplete builds AgentTaskComplete message. lastProgressSeq is the sequence
+// number of the last AgentTaskProgress message sent for this plugin (0 if none was sent), so the
+// service can detect a gap between the streamed progress and this final message.
+func (s *Session) buildAgentTaskComplete(log log.T, res contracts.DocumentResult, instanceId string, lastProgressSeq int64) (result []byte, err error) {
 	uuid.SwitchFormat(uuid.CleanHyphen)
 	messageId := uuid.NewV4()
 	pluginId := res.LastPlugin
@@ -220,7 +291,7 @@ func buildAgentTaskComplete(log log.T, res contracts.DocumentResult, instanceId
 	}
 
 	messageType = mgsContracts.TaskCompleteMessage
-	taskCompletePayload = formatAgentTaskCompletePayload(log, pluginId, res.PluginResults, res.MessageID, instanceId, messageType)
+	taskCompletePayload = formatAgentTaskCompletePayload(log, pluginId, res.PluginResults, res.MessageID, instanceId, messageType, lastProgressSeq)
 	replyBytes, err := json.Marshal(taskCompletePayload)
 	if err != nil {
 		// should not happen
@@ -233,7 +304,7 @@ func buildAgentTaskComplete(log log.T, res contracts.DocumentResult, instanceId
 		MessageType:    messageType,
 		SchemaVersion:  1,
 		CreatedDate:    uint64(time.Now().UnixNano() / 1000000),
-		SequenceNumber: 0,
+		SequenceNumber: s.nextSequenceNumber(),
 		Flags:          0,
 		MessageId:      messageId,
 		Payload:        replyBytes,
@@ -248,7 +319,8 @@ func formatAgentTaskCompletePayload(log log.T,
 	outputs map[string]*contracts.PluginResult,
 	sessionId string,
 	instanceId string,
-	topic string) mgsContracts.AgentTaskCompletePayload {
+	topic string,
+	lastProgressSeq int64) mgsContracts.AgentTaskCompletePayload {
 
 	if len(outputs) < 1 {
 		log.Error("Error in FormatAgentTaskCompletePayload, the outputs map is empty!")
@@ -269,14 +341,15 @@ func formatAgentTaskCompletePayload(log log.T,
 	}
 
 	payload := mgsContracts.AgentTaskCompletePayload{
-		SchemaVersion:    1,
-		TaskId:           sessionId,
-		Topic:            topic,
-		FinalTaskStatus:  string(pluginOutput.Status),
-		IsRoutingFailure: false,
-		AwsAccountId:     "",
-		InstanceId:       instanceId,
-		Output:           output,
+		SchemaVersion:        1,
+		TaskId:               sessionId,
+		Topic:                topic,
+		FinalTaskStatus:      string(pluginOutput.Status),
+		IsRoutingFailure:     false,
+		AwsAccountId:         "",
+		InstanceId:           instanceId,
+		Output:               output,
+		LastProgressSequence: lastProgressSeq,
 	}
 	return payload
 }
@@ -291,4 +364,4 @@ func getMgsEndpoint(region string) (string, error) {
 	endpointBuilder.WriteString(mgsConfig.HttpsPrefix)
 	endpointBuilder.WriteString(hostName)
 	return endpointBuilder.String(), nil
-}
\ No newline at end of file
+}
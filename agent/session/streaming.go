@@ -0,0 +1,203 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/session/audit"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/gorilla/websocket"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// maxProgressChunkBytes bounds how much output is buffered before a chunk is flushed
+	// regardless of the coalescing timer, so a single AgentTaskProgress message stays small.
+	maxProgressChunkBytes = 4096
+	// progressCoalesceDelay is how long the writer waits for more output before flushing
+	// whatever has been buffered so far.
+	progressCoalesceDelay = 500 * time.Millisecond
+)
+
+// progressReporter is implemented by processors that support pushing partial plugin output
+// ahead of the final DocumentResult. It is an optional extension point: Session probes for it
+// with a type assertion so processors that don't support streaming keep working unmodified.
+type progressReporter interface {
+	RegisterProgressHandler(handler func(sessionId string, pluginId string, chunk string, final bool))
+}
+
+// progressStreamer coalesces a plugin's incremental stdout/stderr into bounded, sequence-numbered
+// AgentTaskProgress messages and flushes them through the control channel.
+type progressStreamer struct {
+	session    *Session
+	instanceId string
+	sessionId  string
+	pluginId   string
+
+	mu    sync.Mutex
+	buf   []byte
+	timer *time.Timer
+}
+
+// newProgressStreamer creates a progressStreamer for one running plugin instance.
+func newProgressStreamer(session *Session, instanceId, sessionId, pluginId string) *progressStreamer {
+	return &progressStreamer{
+		session:    session,
+		instanceId: instanceId,
+		sessionId:  sessionId,
+		pluginId:   pluginId,
+	}
+}
+
+// write appends chunk to the pending buffer, flushing immediately if it has grown past
+// maxProgressChunkBytes and otherwise (re)starting the coalescing timer.
+func (p *progressStreamer) write(chunk string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf = append(p.buf, chunk...)
+
+	if len(p.buf) >= maxProgressChunkBytes {
+		p.flushLocked(false)
+		return
+	}
+
+	if p.timer == nil {
+		p.timer = time.AfterFunc(progressCoalesceDelay, func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.flushLocked(false)
+		})
+	} else {
+		p.timer.Reset(progressCoalesceDelay)
+	}
+}
+
+// finish flushes any buffered output and marks the stream as complete, returning the sequence
+// number of the last AgentTaskProgress message (0 if none was sent) so the caller can carry it
+// on the subsequent TaskCompleteMessage.
+func (p *progressStreamer) finish() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	return p.flushLocked(true)
+}
+
+// flushLocked sends the buffered output as an AgentTaskProgress message. Callers must hold p.mu.
+func (p *progressStreamer) flushLocked(final bool) int64 {
+	if len(p.buf) == 0 && !final {
+		return 0
+	}
+
+	log := p.session.context.Log()
+	seq := p.session.nextSequenceNumber()
+
+	payload := mgsContracts.AgentTaskProgressPayload{
+		SchemaVersion: 1,
+		TaskId:        p.sessionId,
+		Topic:         mgsContracts.TaskProgressMessage,
+		InstanceId:    p.instanceId,
+		Output:        string(p.buf),
+		Sequence:      seq,
+		Final:         final,
+	}
+	p.buf = nil
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("cannot marshal AgentTaskProgress payload: %s", err)
+		return seq
+	}
+
+	uuid.SwitchFormat(uuid.CleanHyphen)
+	agentMessage := &mgsContracts.AgentMessage{
+		MessageType:    mgsContracts.TaskProgressMessage,
+		SchemaVersion:  1,
+		CreatedDate:    uint64(time.Now().UnixNano() / 1000000),
+		SequenceNumber: seq,
+		Flags:          0,
+		MessageId:      uuid.NewV4(),
+		Payload:        payloadBytes,
+	}
+
+	msg, err := agentMessage.Serialize(log)
+	if err != nil {
+		log.Errorf("cannot serialize AgentTaskProgress message: %s", err)
+		return seq
+	}
+
+	if err = p.session.controlChannel.SendMessage(log, msg, websocket.BinaryMessage); err != nil {
+		log.Errorf("error sending AgentTaskProgress message: %s", err)
+	}
+
+	return seq
+}
+
+// handlePluginProgress is registered with the processor (when it supports progressReporter) and
+// is invoked every time a running plugin has new output to push ahead of its final result.
+func (s *Session) handlePluginProgress(sessionId string, pluginId string, chunk string, final bool) {
+	key := sessionId + "/" + pluginId
+
+	s.progressMu.Lock()
+	streamer, ok := s.progressStreams[key]
+	isFirstChunk := !ok
+	if !ok {
+		streamer = newProgressStreamer(s, s.agentConfig.InstanceID, sessionId, pluginId)
+		s.progressStreams[key] = streamer
+	}
+	if final {
+		delete(s.progressStreams, key)
+	}
+	s.progressMu.Unlock()
+
+	if isFirstChunk {
+		if _, auditErr := s.auditSink.Record(audit.EventPluginStart, sessionId, pluginId, nil); auditErr != nil {
+			s.context.Log().Errorf("failed to write plugin start audit entry: %s", auditErr)
+		}
+	}
+
+	streamer.write(chunk)
+	if final {
+		seq := streamer.finish()
+		s.recordLastProgressSequence(key, seq)
+	}
+}
+
+// recordLastProgressSequence remembers the sequence number of the last AgentTaskProgress message
+// sent for key (sessionId/pluginId) so buildAgentTaskComplete can carry it on the TaskCompleteMessage.
+func (s *Session) recordLastProgressSequence(key string, seq int64) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.lastProgressSeq[key] = seq
+}
+
+// lastProgressSequenceFor returns the sequence number of the last AgentTaskProgress message sent
+// for (sessionId, pluginId), or 0 if none was sent, and forgets it: listenReply calls this once
+// per plugin result to stamp the subsequent TaskCompleteMessage, and a plugin only finishes once,
+// so the entry is no longer needed afterward. Without this, lastProgressSeq would grow by one
+// entry per session/plugin for the lifetime of the agent.
+func (s *Session) lastProgressSequenceFor(sessionId string, pluginId string) int64 {
+	key := sessionId + "/" + pluginId
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	seq := s.lastProgressSeq[key]
+	delete(s.lastProgressSeq, key)
+	return seq
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/session/audit"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeControlChannel records every message handed to SendMessage so tests can inspect what the
+// streaming path actually put on the wire.
+type fakeControlChannel struct {
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+func (f *fakeControlChannel) Initialize(context context.T, mgsService service.Service, proc processor.Processor, instanceId string) {
+}
+func (f *fakeControlChannel) SetWebSocket(context context.T, mgsService service.Service, proc processor.Processor, instanceId string) error {
+	return nil
+}
+func (f *fakeControlChannel) Open(log log.T) error  { return nil }
+func (f *fakeControlChannel) Close(log log.T) error { return nil }
+func (f *fakeControlChannel) SendMessage(log log.T, input []byte, inputType int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cp := make([]byte, len(input))
+	copy(cp, input)
+	f.messages = append(f.messages, cp)
+	return nil
+}
+
+func (f *fakeControlChannel) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.messages)
+}
+
+func (f *fakeControlChannel) decodeProgress(t *testing.T, i int) mgsContracts.AgentTaskProgressPayload {
+	f.mu.Lock()
+	raw := f.messages[i]
+	f.mu.Unlock()
+
+	var agentMessage mgsContracts.AgentMessage
+	assert.Nil(t, json.Unmarshal(raw, &agentMessage))
+
+	var payload mgsContracts.AgentTaskProgressPayload
+	assert.Nil(t, json.Unmarshal(agentMessage.Payload, &payload))
+	return payload
+}
+
+// newTestSession builds a Session wired to a fakeControlChannel, without the platform/service
+// setup NewSession performs, so streaming can be tested in isolation.
+func newTestSession(cc *fakeControlChannel) *Session {
+	return &Session{
+		context:         context.NewMockDefault(),
+		agentConfig:     contracts.AgentConfiguration{InstanceID: "i-0123456789"},
+		controlChannel:  cc,
+		progressStreams: map[string]*progressStreamer{},
+		lastProgressSeq: map[string]int64{},
+		auditSink:       audit.NewMemorySink(),
+	}
+}
+
+func TestHandlePluginProgress_FlushesOnSize(t *testing.T) {
+	cc := &fakeControlChannel{}
+	s := newTestSession(cc)
+
+	chunk := strings.Repeat("a", maxProgressChunkBytes)
+	s.handlePluginProgress("session-1", "plugin-1", chunk, false)
+
+	assert.Equal(t, 1, cc.sentCount(), "a chunk at the size threshold should flush immediately instead of waiting for the coalescing timer")
+	payload := cc.decodeProgress(t, 0)
+	assert.Equal(t, chunk, payload.Output)
+	assert.False(t, payload.Final)
+	assert.Equal(t, int64(1), payload.Sequence)
+}
+
+func TestHandlePluginProgress_FlushesOnTimer(t *testing.T) {
+	cc := &fakeControlChannel{}
+	s := newTestSession(cc)
+
+	s.handlePluginProgress("session-2", "plugin-1", "small chunk", false)
+	assert.Equal(t, 0, cc.sentCount(), "a small chunk should not flush before the coalescing timer fires")
+
+	assert.Eventually(t, func() bool {
+		return cc.sentCount() == 1
+	}, 2*time.Second, 10*time.Millisecond, "expected the coalescing timer to flush the buffered chunk")
+
+	payload := cc.decodeProgress(t, 0)
+	assert.Equal(t, "small chunk", payload.Output)
+	assert.False(t, payload.Final)
+}
+
+func TestHandlePluginProgress_LastSequenceCarriedToTaskComplete(t *testing.T) {
+	cc := &fakeControlChannel{}
+	s := newTestSession(cc)
+
+	sessionId := "session-3"
+	pluginId := "plugin-1"
+
+	chunk := strings.Repeat("b", maxProgressChunkBytes)
+	s.handlePluginProgress(sessionId, pluginId, chunk, false)
+	s.handlePluginProgress(sessionId, pluginId, "tail", true)
+
+	assert.Equal(t, 2, cc.sentCount())
+	lastChunkPayload := cc.decodeProgress(t, 1)
+	assert.True(t, lastChunkPayload.Final)
+
+	lastProgressSeq := s.lastProgressSequenceFor(sessionId, pluginId)
+	assert.Equal(t, lastChunkPayload.Sequence, lastProgressSeq)
+	assert.True(t, lastProgressSeq > 0)
+
+	res := contracts.DocumentResult{
+		MessageID:  sessionId,
+		LastPlugin: pluginId,
+		PluginResults: map[string]*contracts.PluginResult{
+			pluginId: {
+				Status: "Success",
+				Output: "final output",
+			},
+		},
+	}
+
+	msgBytes, err := s.buildAgentTaskComplete(s.context.Log(), res, "i-0123456789", lastProgressSeq)
+	assert.Nil(t, err)
+
+	var agentMessage mgsContracts.AgentMessage
+	assert.Nil(t, json.Unmarshal(msgBytes, &agentMessage))
+
+	var completePayload mgsContracts.AgentTaskCompletePayload
+	assert.Nil(t, json.Unmarshal(agentMessage.Payload, &completePayload))
+
+	assert.Equal(t, lastProgressSeq, completePayload.LastProgressSequence)
+}
+
+// TestExecutePlugin_StreamsProgressThroughRegisteredHandler exercises the processor-side hook end
+// to end: it registers Session.handlePluginProgress with a real EngineProcessor exactly as
+// ModuleExecute does, then drives output through EngineProcessor.ExecutePlugin (the one call site
+// that invokes reportProgress) rather than calling handlePluginProgress directly, and checks both
+// the streamed AgentTaskProgress messages and the final DocumentResult reach their destinations.
+func TestExecutePlugin_StreamsProgressThroughRegisteredHandler(t *testing.T) {
+	cc := &fakeControlChannel{}
+	s := newTestSession(cc)
+
+	proc := processor.NewEngineProcessor(context.NewMockDefault(), 1, 1, []contracts.DocumentType{contracts.StartSession})
+	resultChan, err := proc.Start()
+	assert.Nil(t, err)
+
+	reporter, ok := processor.Processor(proc).(progressReporter)
+	assert.True(t, ok, "EngineProcessor should implement progressReporter")
+	reporter.RegisterProgressHandler(s.handlePluginProgress)
+
+	sessionId := "session-4"
+	pluginId := "plugin-1"
+	chunk := strings.Repeat("c", maxProgressChunkBytes)
+
+	result := contracts.DocumentResult{
+		MessageID:  sessionId,
+		LastPlugin: pluginId,
+		PluginResults: map[string]*contracts.PluginResult{
+			pluginId: {Status: "Success", Output: "final output"},
+		},
+	}
+
+	go proc.ExecutePlugin(sessionId, pluginId, []string{chunk}, result)
+
+	select {
+	case res := <-resultChan:
+		assert.Equal(t, result, res)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected ExecutePlugin to publish its DocumentResult on the processor's result channel")
+	}
+
+	assert.Eventually(t, func() bool {
+		return cc.sentCount() == 2
+	}, 2*time.Second, 10*time.Millisecond, "expected ExecutePlugin's chunk and final-flush progress messages to reach the control channel")
+
+	firstPayload := cc.decodeProgress(t, 0)
+	assert.Equal(t, chunk, firstPayload.Output)
+	assert.False(t, firstPayload.Final)
+
+	finalPayload := cc.decodeProgress(t, 1)
+	assert.True(t, finalPayload.Final)
+}